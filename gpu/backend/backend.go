@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package backend defines the interface between the GPU-agnostic paint
+// code and its concrete backends (gpu/gl, gpu/vk, ...), along with the
+// shader reflection data produced by internal/cmd/convertshaders.
+package backend
+
+// ShaderSources holds the textual and binary forms of a compiled shader,
+// one set per GPU API, plus the reflection data describing its inputs,
+// uniforms and textures. It is populated by the generated shaders.go
+// files, not hand-written.
+type ShaderSources struct {
+	Inputs   []InputLocation
+	Uniforms UniformsReflection
+	Textures []TextureBinding
+
+	GLSL100ES string
+	GLSL300ES string
+	GLSL130   string
+	GLSL150   string
+	HLSL      []byte
+	SPIRV     []byte
+	WGSL      string
+	DXIL      []byte
+}
+
+// InputLocation describes a single vertex shader input.
+type InputLocation struct {
+	Name          string
+	Location      int
+	Semantic      string
+	SemanticIndex int
+	Type          DataType
+	Size          int
+}
+
+// UniformsReflection describes the uniform blocks and their members used
+// by a shader.
+type UniformsReflection struct {
+	Blocks    []UniformBlock
+	Locations []UniformLocation
+	Size      int
+}
+
+// UniformBlock names a uniform buffer and where it's bound.
+type UniformBlock struct {
+	Name string
+	// Set is the descriptor set index, for backends with Vulkan-style
+	// descriptor sets. GL ignores it.
+	Set     int
+	Binding int
+}
+
+// UniformLocation describes a single member of a uniform block.
+type UniformLocation struct {
+	Name   string
+	Type   DataType
+	Size   int
+	Offset int
+}
+
+// TextureBinding names a texture/sampler and where it's bound.
+type TextureBinding struct {
+	Name string
+	// Set is the descriptor set index, for backends with Vulkan-style
+	// descriptor sets. GL ignores it.
+	Set     int
+	Binding int
+}
+
+// DataType is the scalar or composite type of a shader input or uniform
+// member.
+type DataType uint8
+
+const (
+	DataTypeFloat DataType = iota
+	DataTypeInt
+	DataTypeUint
+	DataTypeBool
+	DataTypeMat3
+	DataTypeMat4
+)
+
+// Device is the interface a GPU backend (gpu/gl, gpu/vk, ...) implements
+// to execute the draw commands produced by the paint package.
+type Device interface {
+	// Caps returns the capabilities of the device.
+	Caps() Caps
+	// NewTexture creates a new texture.
+	NewTexture(format TextureFormat, width, height int) (Texture, error)
+	// NewBuffer creates a new buffer of the given size.
+	NewBuffer(typ BufferBinding, size int) (Buffer, error)
+	// NewProgram creates a new program from its reflected shader
+	// sources.
+	NewProgram(vertex, fragment ShaderSources) (Program, error)
+	// BeginFrame starts a new frame.
+	BeginFrame()
+	// EndFrame finishes the current frame.
+	EndFrame()
+}
+
+// Caps describes what a Device supports.
+type Caps struct {
+	MaxTextureSize int
+}
+
+// TextureFormat is the pixel format of a Texture.
+type TextureFormat uint8
+
+// BufferBinding is the kind of buffer (vertex, index, uniform, ...) a
+// Buffer is bound as.
+type BufferBinding uint8
+
+// Texture is a backend-allocated texture.
+type Texture interface {
+	Release()
+}
+
+// Buffer is a backend-allocated buffer.
+type Buffer interface {
+	Release()
+}
+
+// Program is a backend-compiled shader program.
+type Program interface {
+	Release()
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package vk implements backend.Device on top of Vulkan, consuming the
+// SPIR-V shader blobs produced by internal/cmd/convertshaders.
+package vk
+
+import (
+	"fmt"
+
+	"gioui.org/gpu/backend"
+)
+
+// Backend is a Vulkan-backed backend.Device. It is currently a skeleton:
+// instance/device setup and the per-command submission are not
+// implemented, and no pipeline is built from
+// backend.ShaderSources.SPIRV: it establishes the shape of the backend
+// and satisfies backend.Device.
+type Backend struct {
+	caps backend.Caps
+}
+
+// NewBackend creates a Vulkan device. It always returns an error for now;
+// there is no instance/device/swapchain setup yet.
+func NewBackend() (*Backend, error) {
+	return nil, fmt.Errorf("gpu/vk: Vulkan backend not implemented yet")
+}
+
+func (b *Backend) Caps() backend.Caps {
+	return b.caps
+}
+
+func (b *Backend) NewTexture(format backend.TextureFormat, width, height int) (backend.Texture, error) {
+	return nil, fmt.Errorf("gpu/vk: NewTexture not implemented")
+}
+
+func (b *Backend) NewBuffer(typ backend.BufferBinding, size int) (backend.Buffer, error) {
+	return nil, fmt.Errorf("gpu/vk: NewBuffer not implemented")
+}
+
+// NewProgram checks that the vertex and fragment shaders both carry a
+// SPIR-V blob. It does not create a Vulkan pipeline: it returns an error
+// on every call.
+func (b *Backend) NewProgram(vertex, fragment backend.ShaderSources) (backend.Program, error) {
+	if len(vertex.SPIRV) == 0 || len(fragment.SPIRV) == 0 {
+		return nil, fmt.Errorf("gpu/vk: NewProgram: shader is missing its SPIR-V blob")
+	}
+	return nil, fmt.Errorf("gpu/vk: NewProgram: pipeline creation not implemented")
+}
+
+func (b *Backend) BeginFrame() {}
+
+func (b *Backend) EndFrame() {}
+
+var _ backend.Device = (*Backend)(nil)
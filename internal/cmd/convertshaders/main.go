@@ -3,15 +3,21 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -21,10 +27,17 @@ import (
 // This program generates shader variants for
 // multiple GPU backends (OpenGL ES, Direct3D 11...)
 // from a single source.
+//
+// Besides the textual GLSL/HLSL variants, it also emits a SPIR-V
+// binary for each shader so that Vulkan/WebGPU-capable backends
+// (gpu/vk, gpu/wgpu) can consume the same generated sources without
+// a separate compilation pipeline.
 
 var (
 	packageName   = flag.String("package", "", "specify Go package name")
 	shadersDir    = flag.String("dir", "shaders", "specify shader directory")
+	genWGSL       = flag.Bool("wgsl", false, "generate WGSL output for a WebGPU backend (requires naga)")
+	genDXC        = flag.Bool("dxc", false, "also generate DXIL (shader model 6.x) output via dxc, for a future gpu/d3d12 backend")
 	absShadersDir string
 )
 
@@ -33,6 +46,93 @@ type shaderArgs struct {
 	Header         string
 }
 
+// defaultShaderArgs and textureShaderArgs are the two hardcoded variants
+// the generator compiled unconditionally before //gio:variant pragmas
+// existed. They're still the fallback for any shader that hasn't been
+// given a pragma, so a shader whose template actually branches on
+// {{.FetchColorExpr}}/{{.Header}} keeps getting both variants compiled
+// until it's migrated.
+var defaultShaderArgs = shaderArgs{
+	FetchColorExpr: `_color`,
+	Header:         `layout(binding=0) uniform Color { vec4 _color; };`,
+}
+
+var textureShaderArgs = shaderArgs{
+	FetchColorExpr: `texture(tex, vUV)`,
+	Header:         `layout(binding=0) uniform sampler2D tex;`,
+}
+
+// shaderVariant names one fill mode of a shader, declared by a
+// "//gio:variant name=... header=... color=..." pragma at the top of the
+// .frag/.vert file.
+type shaderVariant struct {
+	Name string
+	Args shaderArgs
+}
+
+// variantPragmaRe matches key="value" pairs in a //gio:variant pragma.
+// Values must be double-quoted: header and color snippets are full of
+// spaces and braces, and a missing quote would otherwise silently
+// truncate them at the first space with no error raised.
+var variantPragmaRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// parseVariantPragmas reads the //gio:variant pragmas from the top of a
+// shader file. It stops at the first non-comment, non-blank line, and
+// returns no variants (rather than an error) when none are declared, so
+// callers can fall back to single-variant mode.
+func parseVariantPragmas(path string) ([]shaderVariant, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var variants []shaderVariant
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "//"))
+		if !strings.HasPrefix(line, "gio:variant") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "gio:variant"))
+		rest := line
+		var v shaderVariant
+		for _, m := range variantPragmaRe.FindAllStringSubmatch(line, -1) {
+			switch m[1] {
+			case "name":
+				v.Name = m[2]
+			case "header":
+				v.Args.Header = m[2]
+			case "color":
+				v.Args.FetchColorExpr = m[2]
+			}
+			rest = strings.Replace(rest, m[0], "", 1)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("%s: malformed //gio:variant pragma (values must be double-quoted): %q", path, line)
+		}
+		if v.Name == "" {
+			return nil, fmt.Errorf("%s: //gio:variant pragma missing name", path)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("%s: duplicate //gio:variant name %q", path, v.Name)
+		}
+		seen[v.Name] = true
+		variants = append(variants, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
 func main() {
 	flag.Parse()
 	if err := generate(); err != nil {
@@ -51,6 +151,10 @@ func generate() error {
 	if err != nil {
 		return err
 	}
+	glslccVer, err := glslccVersion(glslcc)
+	if err != nil {
+		return err
+	}
 	absShadersDir, err = filepath.Abs(*shadersDir)
 	if err != nil {
 		return err
@@ -59,6 +163,14 @@ func generate() error {
 	if err != nil {
 		return err
 	}
+	// glslcc resolves #include directives against absShadersDir, so the
+	// cache key for any compile must account for every file there, not
+	// just the entry-point shader: editing a shared header changes
+	// glslcc's output without changing the entry file's own bytes.
+	includeHash, err := shaderDirHash(absShadersDir)
+	if err != nil {
+		return err
+	}
 	var out bytes.Buffer
 	out.WriteString("// Code generated by build.go. DO NOT EDIT.\n\n")
 	fmt.Fprintf(&out, "package %s\n\n", *packageName)
@@ -70,41 +182,55 @@ func generate() error {
 		if ext := filepath.Ext(shader); ext != ".vert" && ext != ".frag" {
 			continue
 		}
-		const nvariants = 2
-		var variants [nvariants]struct {
-			backend.ShaderSources
-			hlslSrc string
+		variantDefs, err := parseVariantPragmas(shader)
+		if err != nil {
+			return err
 		}
-		args := [nvariants]shaderArgs{
-			{
-				FetchColorExpr: `_color`,
-				Header:         `layout(binding=0) uniform Color { vec4 _color; };`,
-			},
-			{
-				FetchColorExpr: `texture(tex, vUV)`,
-				Header:         `layout(binding=0) uniform sampler2D tex;`,
-			},
+		// Shaders without //gio:variant pragmas compile against both
+		// hardcoded variants the generator always defaulted to before
+		// the pragma system existed, so unmigrated shaders keep getting
+		// the same two {{.FetchColorExpr}}/{{.Header}} substitutions
+		// they always got.
+		named := len(variantDefs) > 0
+		if !named {
+			variantDefs = []shaderVariant{{Args: defaultShaderArgs}, {Args: textureShaderArgs}}
 		}
-		for i := range args {
-			glsl100es, reflect, err := convertShader(tmp, glslcc, shader, "gles", "100", &args[i], false)
+		results := make([]struct {
+			backend.ShaderSources
+			hlslSrc string
+		}, len(variantDefs))
+		for i, variant := range variantDefs {
+			args := variant.Args
+			glsl100es, reflect, err := convertShader(tmp, glslcc, glslccVer, includeHash, shader, "gles", "100", &args, false)
 			if err != nil {
 				return err
 			}
-			if err := parseReflection(reflect, &variants[i].ShaderSources); err != nil {
+			if err := parseReflection(reflect, &results[i].ShaderSources); err != nil {
+				return err
+			}
+			glsl300es, _, err := convertShader(tmp, glslcc, glslccVer, includeHash, shader, "gles", "300", &args, false)
+			if err != nil {
 				return err
 			}
-			glsl300es, _, err := convertShader(tmp, glslcc, shader, "gles", "300", &args[i], false)
+			glsl130, _, err := convertShader(tmp, glslcc, glslccVer, includeHash, shader, "glsl", "130", &args, false)
 			if err != nil {
 				return err
 			}
-			glsl130, _, err := convertShader(tmp, glslcc, shader, "glsl", "130", &args[i], false)
+			hlsl, _, err := convertShader(tmp, glslcc, glslccVer, includeHash, shader, "hlsl", "40", &args, false)
 			if err != nil {
 				return err
 			}
-			hlsl, _, err := convertShader(tmp, glslcc, shader, "hlsl", "40", &args[i], false)
+			spirv, err := compileSPIRV(tmp, glslcc, glslccVer, includeHash, shader, &args)
 			if err != nil {
 				return err
 			}
+			var wgsl string
+			if *genWGSL {
+				wgsl, err = compileWGSLCached(tmp, spirv)
+				if err != nil {
+					return err
+				}
+			}
 			var hlslProf string
 			switch filepath.Ext(shader) {
 			case ".frag":
@@ -115,36 +241,60 @@ func generate() error {
 				return fmt.Errorf("unrecognized shader type %s", shader)
 			}
 			var hlslc []byte
-			hlslc, err = compileHLSL(hlsl, "main", hlslProf+"_4_0_level_9_1")
+			hlslc, err = compileHLSLCached(hlsl, "main", hlslProf+"_4_0_level_9_1")
 			if err != nil {
 				// Attempt shader model 4.0. Only the app/headless
 				// test shaders use features not supported by level
 				// 9.1.
-				hlslc, err = compileHLSL(hlsl, "main", hlslProf+"_4_0")
+				hlslc, err = compileHLSLCached(hlsl, "main", hlslProf+"_4_0")
 				if err != nil {
 					return err
 				}
 			}
+			var dxil []byte
+			if *genDXC {
+				if _, err := exec.LookPath("dxc"); err != nil {
+					// dxc not installed: fall back to DXBC-only, as
+					// building a gpu/d3d12 backend isn't a goal of
+					// this machine.
+				} else {
+					dxil, err = compileDXILCached(hlsl, "main", hlslProf+"_6_0")
+					if err != nil {
+						return err
+					}
+				}
+			}
 			// OpenGL 3.2 Core only accepts GLSL version 1.50, but is
 			// otherwise compatible with version 1.30.
 			glsl150 := strings.Replace(glsl130, "#version 130", "#version 150", 1)
-			variants[i].GLSL100ES = glsl100es
-			variants[i].GLSL300ES = glsl300es
-			variants[i].GLSL130 = glsl130
-			variants[i].GLSL150 = glsl150
-			variants[i].hlslSrc = hlsl
-			variants[i].HLSL = hlslc
+			results[i].GLSL100ES = glsl100es
+			results[i].GLSL300ES = glsl300es
+			results[i].GLSL130 = glsl130
+			results[i].GLSL150 = glsl150
+			results[i].hlslSrc = hlsl
+			results[i].HLSL = hlslc
+			results[i].SPIRV = spirv
+			results[i].WGSL = wgsl
+			results[i].DXIL = dxil
 		}
+		// Unmigrated shaders that don't actually branch on the variant
+		// args compile identically both times; collapse back down to a
+		// single value, exactly as the old 2-variant scheme did via its
+		// own multiVariant check.
+		multiVariant := !named && len(results) > 1 && results[0].GLSL100ES != results[1].GLSL100ES
+		emitMulti := named || multiVariant
 		name := filepath.Base(shader)
 		name = strings.ReplaceAll(name, ".", "_")
 		fmt.Fprintf(&out, "\tshader_%s = ", name)
-		// If the shader don't use the variant arguments, output
-		// only a single version.
-		multiVariant := variants[0].GLSL100ES != variants[1].GLSL100ES
-		if multiVariant {
+		if named {
+			fmt.Fprintf(&out, "map[string]backend.ShaderSources{\n")
+		} else if multiVariant {
 			fmt.Fprintf(&out, "[...]backend.ShaderSources{\n")
 		}
-		for _, src := range variants {
+		for i, src := range results {
+			if named {
+				fmt.Fprintf(&out, "%q: ", variantDefs[i].Name)
+			}
 			fmt.Fprintf(&out, "backend.ShaderSources{\n")
 			if len(src.Inputs) > 0 {
 				fmt.Fprintf(&out, "Inputs: %#v,\n", src.Inputs)
@@ -165,16 +315,23 @@ func generate() error {
 			fmt.Fprintf(&out, "GLSL150: %#v,\n", src.GLSL150)
 			fmt.Fprintf(&out, "/*\n%s\n*/\n", src.hlslSrc)
 			fmt.Fprintf(&out, "HLSL: %#v,\n", src.HLSL)
+			fmt.Fprintf(&out, "SPIRV: %#v,\n", src.SPIRV)
+			if src.WGSL != "" {
+				fmt.Fprintf(&out, "WGSL: %#v,\n", src.WGSL)
+			}
+			if len(src.DXIL) > 0 {
+				fmt.Fprintf(&out, "DXIL: %#v,\n", src.DXIL)
+			}
 			fmt.Fprintf(&out, "}")
-			if multiVariant {
+			if emitMulti {
 				fmt.Fprintf(&out, ",")
 			}
 			fmt.Fprintf(&out, "\n")
-			if !multiVariant {
+			if !named && !multiVariant {
 				break
 			}
 		}
-		if multiVariant {
+		if emitMulti {
 			fmt.Fprintf(&out, "}\n")
 		}
 	}
@@ -188,6 +345,140 @@ func generate() error {
 	return cmd.Run()
 }
 
+// glslccVersion returns the glslcc version string, included in cache keys
+// so a compiler upgrade invalidates previously cached output.
+func glslccVersion(glslcc string) (string, error) {
+	out, err := exec.Command(glslcc, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("glslcc --version: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// toolIdentity resolves exe on PATH and returns a string identifying that
+// binary's size and modification time, for inclusion in a cache key.
+// Unlike glslcc, fxc and dxc have no stable --version output to rely on,
+// so a compiler upgrade is detected by the binary on disk changing
+// instead; without this, bytecode from an old fxc/dxc keeps being served
+// from the cache after an upgrade that touched no shader source.
+func toolIdentity(exe string) (string, error) {
+	path, err := exec.LookPath(exe)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()), nil
+}
+
+// shaderDirHash hashes every file in dir, so a compile's cache key can
+// account for glslcc's --include-dirs resolution of #include directives
+// and not just the entry-point shader it was given: glslcc's actual
+// output depends on whatever shared headers the entry file pulls in from
+// dir, and we don't have a dependency list to hash more precisely.
+func shaderDirHash(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	h := sha256.New()
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return "", err
+		}
+		if info.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, filepath.Base(f))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheDir returns the root of the content-addressed shader cache, rooted
+// under GOCACHE like other Go build artifacts.
+func cacheDir() (string, error) {
+	dir := os.Getenv("GOCACHE")
+	if dir == "" {
+		out, err := exec.Command("go", "env", "GOCACHE").Output()
+		if err != nil {
+			return "", fmt.Errorf("cacheDir: %v", err)
+		}
+		dir = strings.TrimSpace(string(out))
+	}
+	return filepath.Join(dir, "gio-shaders"), nil
+}
+
+// cacheKey hashes the compilation inputs (rendered source, glslcc version,
+// target parameters) into a content-addressed cache key.
+func cacheKey(source []byte, parts ...string) string {
+	h := sha256.New()
+	h.Write(source)
+	for _, p := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheLoad looks up key in the shader cache, returning the contents of
+// key.<ext> for each requested ext. It reports ok == false on any miss.
+func cacheLoad(key string, exts ...string) (map[string][]byte, bool) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, false
+	}
+	data := make(map[string][]byte, len(exts))
+	for _, ext := range exts {
+		b, err := ioutil.ReadFile(filepath.Join(dir, key+"."+ext))
+		if err != nil {
+			return nil, false
+		}
+		data[ext] = b
+	}
+	return data, true
+}
+
+// cacheStore writes entries (keyed by extension) into the shader cache
+// under key. Each entry is written to a temporary file in dir and renamed
+// into place, so concurrent `go generate` invocations sharing $GOCACHE
+// never observe a partially-written cache entry. Failures are non-fatal:
+// a cold cache just means the next build shells out to glslcc again.
+func cacheStore(key string, entries map[string][]byte) {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	for ext, data := range entries {
+		tmp, err := ioutil.TempFile(dir, key+".*."+ext+".tmp")
+		if err != nil {
+			continue
+		}
+		if _, err := tmp.Write(data); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			continue
+		}
+		tmp.Close()
+		if err := os.Rename(tmp.Name(), filepath.Join(dir, key+"."+ext)); err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+}
+
 func parseReflection(jsonData []byte, info *backend.ShaderSources) error {
 	type InputReflection struct {
 		ID            int    `json:"id"`
@@ -258,6 +549,7 @@ func parseReflection(jsonData []byte, info *backend.ShaderSources) error {
 	for _, block := range shaderBlocks {
 		info.Uniforms.Blocks = append(info.Uniforms.Blocks, backend.UniformBlock{
 			Name:    block.Name,
+			Set:     block.Set,
 			Binding: block.Binding,
 		})
 		for _, member := range block.Members {
@@ -283,13 +575,30 @@ func parseReflection(jsonData []byte, info *backend.ShaderSources) error {
 	for _, texture := range textures {
 		info.Textures = append(info.Textures, backend.TextureBinding{
 			Name:    texture.Name,
+			Set:     texture.Set,
 			Binding: texture.Binding,
 		})
 	}
 	return nil
 }
 
+// parseDataType translates a glslcc reflection type name into a
+// backend.DataType and its component count. Array types are reflected as
+// "<elem>[<n>]"; the returned count is the total number of base components
+// (n times the element's own component count) so callers can derive the
+// uniform block stride without special-casing arrays.
 func parseDataType(t string) (backend.DataType, int, error) {
+	if i := strings.IndexByte(t, '['); i != -1 {
+		n, err := strconv.Atoi(strings.TrimSuffix(t[i+1:], "]"))
+		if err != nil {
+			return 0, 0, fmt.Errorf("unsupported input data type: %s", t)
+		}
+		typ, size, err := parseDataType(t[:i])
+		if err != nil {
+			return 0, 0, err
+		}
+		return typ, size * n, nil
+	}
 	switch t {
 	case "float":
 		return backend.DataTypeFloat, 1, nil
@@ -299,6 +608,12 @@ func parseDataType(t string) (backend.DataType, int, error) {
 		return backend.DataTypeFloat, 3, nil
 	case "float4":
 		return backend.DataTypeFloat, 4, nil
+	case "float3x3":
+		// std140 pads each column of a mat3 to a vec4, so its uniform
+		// block footprint is 3 columns * 4 components, not 3*3.
+		return backend.DataTypeMat3, 12, nil
+	case "float4x4":
+		return backend.DataTypeMat4, 16, nil
 	case "int":
 		return backend.DataTypeInt, 1, nil
 	case "int2":
@@ -307,12 +622,28 @@ func parseDataType(t string) (backend.DataType, int, error) {
 		return backend.DataTypeInt, 3, nil
 	case "int4":
 		return backend.DataTypeInt, 4, nil
+	case "uint":
+		return backend.DataTypeUint, 1, nil
+	case "uint2":
+		return backend.DataTypeUint, 2, nil
+	case "uint3":
+		return backend.DataTypeUint, 3, nil
+	case "uint4":
+		return backend.DataTypeUint, 4, nil
+	case "bool":
+		return backend.DataTypeBool, 1, nil
+	case "bool2":
+		return backend.DataTypeBool, 2, nil
+	case "bool3":
+		return backend.DataTypeBool, 3, nil
+	case "bool4":
+		return backend.DataTypeBool, 4, nil
 	default:
 		return 0, 0, fmt.Errorf("unsupported input data type: %s", t)
 	}
 }
 
-func convertShader(tmp, glslcc, path, lang, profile string, args *shaderArgs, flattenUBOs bool) (string, []byte, error) {
+func convertShader(tmp, glslcc, glslccVer, includeHash, path, lang, profile string, args *shaderArgs, flattenUBOs bool) (string, []byte, error) {
 	shaderTmpl, err := template.ParseFiles(path)
 	if err != nil {
 		return "", nil, err
@@ -321,6 +652,10 @@ func convertShader(tmp, glslcc, path, lang, profile string, args *shaderArgs, fl
 	if err := shaderTmpl.Execute(&buf, args); err != nil {
 		return "", nil, err
 	}
+	key := cacheKey(buf.Bytes(), glslccVer, includeHash, lang, profile, fmt.Sprint(flattenUBOs))
+	if data, ok := cacheLoad(key, "src", "json"); ok {
+		return string(data["src"]), data["json"], nil
+	}
 	tmppath := filepath.Join(tmp, filepath.Base(path))
 	if err := ioutil.WriteFile(tmppath, buf.Bytes(), 0644); err != nil {
 		return "", nil, err
@@ -373,5 +708,216 @@ func convertShader(tmp, glslcc, path, lang, profile string, args *shaderArgs, fl
 	if err != nil {
 		return "", nil, err
 	}
+	cacheStore(key, map[string][]byte{"src": src, "json": reflect})
 	return string(src), reflect, nil
 }
+
+// compileHLSLCached wraps compileHLSL with the content-addressed shader
+// cache: fxc is the slowest step in the pipeline, so a warm cache hit here
+// is most of the win a cached build gets. The cache key includes fxc's
+// own identity on disk (see toolIdentity) so an fxc upgrade invalidates
+// previously cached bytecode.
+func compileHLSLCached(hlsl, entrypoint, profile string) ([]byte, error) {
+	fxcID, err := toolIdentity("fxc")
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey([]byte(hlsl), "hlslbin", entrypoint, profile, fxcID)
+	if data, ok := cacheLoad(key, "dxbc"); ok {
+		return data["dxbc"], nil
+	}
+	hlslc, err := compileHLSL(hlsl, entrypoint, profile)
+	if err != nil {
+		return nil, err
+	}
+	cacheStore(key, map[string][]byte{"dxbc": hlslc})
+	return hlslc, nil
+}
+
+// compileSPIRV invokes glslcc to produce a SPIR-V binary for the shader at
+// path, for consumption by Vulkan/WebGPU-capable backends.
+func compileSPIRV(tmp, glslcc, glslccVer, includeHash, path string, args *shaderArgs) ([]byte, error) {
+	shaderTmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := shaderTmpl.Execute(&buf, args); err != nil {
+		return nil, err
+	}
+	key := cacheKey(buf.Bytes(), glslccVer, includeHash, "spirv")
+	if data, ok := cacheLoad(key, "spv"); ok {
+		return data["spv"], nil
+	}
+	tmppath := filepath.Join(tmp, filepath.Base(path))
+	if err := ioutil.WriteFile(tmppath, buf.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmppath)
+	var progFlag string
+	var progSuffix string
+	switch filepath.Ext(path) {
+	case ".vert":
+		progFlag = "--vert"
+		progSuffix = "vs"
+	case ".frag":
+		progFlag = "--frag"
+		progSuffix = "fs"
+	default:
+		return nil, fmt.Errorf("unrecognized shader type: %s", path)
+	}
+	cmd := exec.Command(glslcc,
+		"--silent",
+		"--optimize",
+		"--include-dirs", absShadersDir,
+		"--output", filepath.Join(tmp, "shader"),
+		"--lang", "spirv",
+		progFlag, tmppath,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	outpath := filepath.Join(tmp, "shader_"+progSuffix+".spv")
+	defer os.Remove(outpath)
+	spirv, err := ioutil.ReadFile(outpath)
+	if err != nil {
+		return nil, err
+	}
+	cacheStore(key, map[string][]byte{"spv": spirv})
+	return spirv, nil
+}
+
+// compileWGSL translates a SPIR-V binary into WGSL using naga. naga's
+// SPIR-V front end already accounts for WGSL's clip-space convention
+// (Y-flip and 0..1 depth range) as part of lowering Vulkan-style SPIR-V,
+// so the CLI invocation is just the input and output paths.
+func compileWGSL(tmp string, spirv []byte) (string, error) {
+	naga, err := exec.LookPath("naga")
+	if err != nil {
+		return "", fmt.Errorf("compileWGSL: naga not found: %v", err)
+	}
+	inpath := filepath.Join(tmp, "shader.spv")
+	if err := ioutil.WriteFile(inpath, spirv, 0644); err != nil {
+		return "", err
+	}
+	defer os.Remove(inpath)
+	outpath := filepath.Join(tmp, "shader.wgsl")
+	defer os.Remove(outpath)
+	cmd := exec.Command(naga, inpath, outpath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("compileWGSL: %v", err)
+	}
+	wgsl, err := ioutil.ReadFile(outpath)
+	if err != nil {
+		return "", err
+	}
+	return string(wgsl), nil
+}
+
+// compileWGSLCached wraps compileWGSL with the content-addressed shader
+// cache, the same way compileDXILCached wraps compileDXIL, so a warm
+// -wgsl build doesn't reinvoke naga on every run. The cache key includes
+// naga's own identity on disk (see toolIdentity) so a naga upgrade
+// invalidates previously cached WGSL.
+func compileWGSLCached(tmp string, spirv []byte) (string, error) {
+	nagaID, err := toolIdentity("naga")
+	if err != nil {
+		return "", err
+	}
+	key := cacheKey(spirv, "wgsl", nagaID)
+	if data, ok := cacheLoad(key, "wgsl"); ok {
+		return string(data["wgsl"]), nil
+	}
+	wgsl, err := compileWGSL(tmp, spirv)
+	if err != nil {
+		return "", err
+	}
+	cacheStore(key, map[string][]byte{"wgsl": []byte(wgsl)})
+	return wgsl, nil
+}
+
+// compileHLSL invokes fxc to compile hlsl into DXBC bytecode for the
+// given entry point and shader profile (e.g. "ps_4_0").
+func compileHLSL(hlsl, entrypoint, profile string) ([]byte, error) {
+	fxc, err := exec.LookPath("fxc")
+	if err != nil {
+		return nil, fmt.Errorf("compileHLSL: %v", err)
+	}
+	tmp, err := ioutil.TempDir("", "fxc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	inpath := filepath.Join(tmp, "shader.hlsl")
+	if err := ioutil.WriteFile(inpath, []byte(hlsl), 0644); err != nil {
+		return nil, err
+	}
+	outpath := filepath.Join(tmp, "shader.dxbc")
+	cmd := exec.Command(fxc,
+		"/T", profile,
+		"/E", entrypoint,
+		"/Fo", outpath,
+		inpath,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("compileHLSL: %v", err)
+	}
+	return ioutil.ReadFile(outpath)
+}
+
+// compileDXIL invokes dxc to compile hlsl into DXIL bytecode at shader
+// model 6.0+, for a future gpu/d3d12 backend. Callers should check that
+// dxc is on PATH first; unlike compileHLSL's fxc path, there's no older
+// profile to fall back to.
+func compileDXIL(hlsl, entrypoint, profile string) ([]byte, error) {
+	tmp, err := ioutil.TempDir("", "dxc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	inpath := filepath.Join(tmp, "shader.hlsl")
+	if err := ioutil.WriteFile(inpath, []byte(hlsl), 0644); err != nil {
+		return nil, err
+	}
+	outpath := filepath.Join(tmp, "shader.dxil")
+	cmd := exec.Command("dxc",
+		"-T", profile,
+		"-E", entrypoint,
+		"-Fo", outpath,
+		inpath,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("compileDXIL: %v", err)
+	}
+	return ioutil.ReadFile(outpath)
+}
+
+// compileDXILCached wraps compileDXIL with the content-addressed shader
+// cache, the same way compileHLSLCached wraps compileHLSL, so a warm
+// -dxc build doesn't reinvoke dxc on every run. The cache key includes
+// dxc's own identity on disk (see toolIdentity) so a dxc upgrade
+// invalidates previously cached bytecode.
+func compileDXILCached(hlsl, entrypoint, profile string) ([]byte, error) {
+	dxcID, err := toolIdentity("dxc")
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey([]byte(hlsl), "dxil", entrypoint, profile, dxcID)
+	if data, ok := cacheLoad(key, "dxil"); ok {
+		return data["dxil"], nil
+	}
+	dxil, err := compileDXIL(hlsl, entrypoint, profile)
+	if err != nil {
+		return nil, err
+	}
+	cacheStore(key, map[string][]byte{"dxil": dxil})
+	return dxil, nil
+}
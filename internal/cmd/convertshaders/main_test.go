@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"testing"
+
+	"gioui.org/gpu/backend"
+)
+
+func TestParseDataType(t *testing.T) {
+	tests := []struct {
+		in      string
+		typ     backend.DataType
+		size    int
+		wantErr bool
+	}{
+		{in: "float", typ: backend.DataTypeFloat, size: 1},
+		{in: "float2", typ: backend.DataTypeFloat, size: 2},
+		{in: "float3", typ: backend.DataTypeFloat, size: 3},
+		{in: "float4", typ: backend.DataTypeFloat, size: 4},
+		// std140 pads each column of a mat3 to a vec4: 3 columns * 4 components.
+		{in: "float3x3", typ: backend.DataTypeMat3, size: 12},
+		{in: "float4x4", typ: backend.DataTypeMat4, size: 16},
+		{in: "int", typ: backend.DataTypeInt, size: 1},
+		{in: "int4", typ: backend.DataTypeInt, size: 4},
+		{in: "uint", typ: backend.DataTypeUint, size: 1},
+		{in: "uint3", typ: backend.DataTypeUint, size: 3},
+		{in: "bool", typ: backend.DataTypeBool, size: 1},
+		{in: "bool2", typ: backend.DataTypeBool, size: 2},
+		// array suffix multiplies the element's own component count.
+		{in: "float4[3]", typ: backend.DataTypeFloat, size: 12},
+		{in: "float3x3[2]", typ: backend.DataTypeMat3, size: 24},
+		{in: "float4[]", wantErr: true},
+		{in: "double", wantErr: true},
+		{in: "float5", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			typ, size, err := parseDataType(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseDataType(%q) = (%v, %v, nil), want error", tc.in, typ, size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDataType(%q) = error %v, want (%v, %v)", tc.in, err, tc.typ, tc.size)
+			}
+			if typ != tc.typ || size != tc.size {
+				t.Errorf("parseDataType(%q) = (%v, %v), want (%v, %v)", tc.in, typ, size, tc.typ, tc.size)
+			}
+		})
+	}
+}
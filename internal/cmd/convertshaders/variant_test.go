@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeShader(t *testing.T, contents string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shader.frag")
+	if err := ioutil.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseVariantPragmas(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     []shaderVariant
+		wantErr  bool
+	}{
+		{
+			name:     "none",
+			contents: "void main() {}\n",
+			want:     nil,
+		},
+		{
+			name: "single",
+			contents: `// gio:variant name="solid" header="layout(binding=0) uniform Color { vec4 _color; };" color="_color"
+void main() {}
+`,
+			want: []shaderVariant{{
+				Name: "solid",
+				Args: shaderArgs{
+					FetchColorExpr: "_color",
+					Header:         "layout(binding=0) uniform Color { vec4 _color; };",
+				},
+			}},
+		},
+		{
+			name: "multiple",
+			contents: `// gio:variant name="solid" color="_color"
+// gio:variant name="tex" color="texture(tex, vUV)"
+void main() {}
+`,
+			want: []shaderVariant{
+				{Name: "solid", Args: shaderArgs{FetchColorExpr: "_color"}},
+				{Name: "tex", Args: shaderArgs{FetchColorExpr: "texture(tex, vUV)"}},
+			},
+		},
+		{
+			name: "missing name",
+			contents: `// gio:variant color="_color"
+void main() {}
+`,
+			wantErr: true,
+		},
+		{
+			name: "unquoted value",
+			contents: `// gio:variant name=solid color="_color"
+void main() {}
+`,
+			wantErr: true,
+		},
+		{
+			name: "trailing garbage",
+			contents: `// gio:variant name="solid" bogus
+void main() {}
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate name",
+			contents: `// gio:variant name="solid" color="_color"
+// gio:variant name="solid" color="texture(tex, vUV)"
+void main() {}
+`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeShader(t, tc.contents)
+			got, err := parseVariantPragmas(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseVariantPragmas() = %v, nil, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseVariantPragmas() error = %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("parseVariantPragmas() = %+v, want %+v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("variant %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}